@@ -0,0 +1,80 @@
+// Package events builds CloudEvents 1.0 envelopes for the events
+// microservice and maps them onto the Kafka topics other services consume,
+// so the wire format stays decoupled from our internal Go structs.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const specVersion = "1.0"
+
+// Source identifies this service as the CloudEvents "source" field.
+const Source = "cinimaabyss/events-service"
+
+// CloudEvent is the CloudEvents 1.0 envelope published to every topic.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	ID              string          `json:"id"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Subject         string          `json:"subject,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// Event types this service emits, using the reverse-DNS convention
+// recommended by the CloudEvents spec.
+const (
+	TypeMovieViewed = "com.cinimaabyss.movie.viewed.v1"
+	TypeMovieRated  = "com.cinimaabyss.movie.rated.v1"
+	TypeMovieAdded  = "com.cinimaabyss.movie.added.v1"
+	TypeMovieOther  = "com.cinimaabyss.movie.v1"
+	TypeUser        = "com.cinimaabyss.user.v1"
+	TypePayment     = "com.cinimaabyss.payment.v1"
+)
+
+// MovieEventType maps a MovieEvent's free-form Action to a CloudEvent type,
+// falling back to TypeMovieOther for actions we don't special-case.
+func MovieEventType(action string) string {
+	switch action {
+	case "viewed":
+		return TypeMovieViewed
+	case "rated":
+		return TypeMovieRated
+	case "added":
+		return TypeMovieAdded
+	default:
+		return TypeMovieOther
+	}
+}
+
+// New builds a CloudEvent envelope wrapping data as the JSON payload.
+func New(eventType, subject string, data interface{}) (CloudEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return CloudEvent{}, fmt.Errorf("marshal event data: %w", err)
+	}
+	return CloudEvent{
+		SpecVersion:     specVersion,
+		Type:            eventType,
+		Source:          Source,
+		ID:              fmt.Sprintf("%s-%d", subject, time.Now().UnixNano()),
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Subject:         subject,
+		Data:            raw,
+	}, nil
+}
+
+// Unmarshal decodes data into v, returning an error if the event carries no
+// payload or the payload doesn't match v's shape.
+func (e CloudEvent) Unmarshal(v interface{}) error {
+	if len(e.Data) == 0 {
+		return fmt.Errorf("cloudevent %s has no data", e.ID)
+	}
+	return json.Unmarshal(e.Data, v)
+}
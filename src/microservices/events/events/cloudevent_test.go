@@ -0,0 +1,60 @@
+package events
+
+import "testing"
+
+func TestNewAndUnmarshal(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	evt, err := New(TypeUser, "user-1", payload{Name: "ada"})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	if evt.SpecVersion != specVersion {
+		t.Errorf("SpecVersion = %q, want %q", evt.SpecVersion, specVersion)
+	}
+	if evt.Type != TypeUser {
+		t.Errorf("Type = %q, want %q", evt.Type, TypeUser)
+	}
+	if evt.Source != Source {
+		t.Errorf("Source = %q, want %q", evt.Source, Source)
+	}
+	if evt.DataContentType != "application/json" {
+		t.Errorf("DataContentType = %q, want application/json", evt.DataContentType)
+	}
+
+	var got payload
+	if err := evt.Unmarshal(&got); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if got.Name != "ada" {
+		t.Errorf("Unmarshal got Name = %q, want ada", got.Name)
+	}
+}
+
+func TestUnmarshalNoData(t *testing.T) {
+	evt := CloudEvent{ID: "empty"}
+	var v struct{}
+	if err := evt.Unmarshal(&v); err == nil {
+		t.Error("Unmarshal with no data: expected error, got nil")
+	}
+}
+
+func TestMovieEventType(t *testing.T) {
+	cases := []struct {
+		action string
+		want   string
+	}{
+		{"viewed", TypeMovieViewed},
+		{"rated", TypeMovieRated},
+		{"added", TypeMovieAdded},
+		{"deleted", TypeMovieOther},
+		{"", TypeMovieOther},
+	}
+	for _, c := range cases {
+		if got := MovieEventType(c.action); got != c.want {
+			t.Errorf("MovieEventType(%q) = %q, want %q", c.action, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Handler processes a CloudEvent consumed from Kafka.
+type Handler func(CloudEvent) error
+
+// Dispatcher routes CloudEvents to handlers registered against a regex
+// matched on the event's type, so a single topic can carry several related
+// event types without the consumer caring which one arrived.
+type Dispatcher struct {
+	routes []route
+}
+
+type route struct {
+	pattern *regexp.Regexp
+	handler Handler
+}
+
+// NewDispatcher returns an empty Dispatcher.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Register associates handler with every CloudEvent type matching pattern.
+func (d *Dispatcher) Register(pattern string, handler Handler) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("compile dispatch pattern %q: %w", pattern, err)
+	}
+	d.routes = append(d.routes, route{pattern: re, handler: handler})
+	return nil
+}
+
+// Dispatch runs the handlers whose pattern matches evt.Type in registration
+// order, returning an error if none matched. It stops at the first handler
+// that fails and returns its error; later matching handlers are not run.
+func (d *Dispatcher) Dispatch(evt CloudEvent) error {
+	var matched bool
+	for _, r := range d.routes {
+		if r.pattern.MatchString(evt.Type) {
+			matched = true
+			if err := r.handler(evt); err != nil {
+				return err
+			}
+		}
+	}
+	if !matched {
+		return fmt.Errorf("no handler registered for event type %q", evt.Type)
+	}
+	return nil
+}
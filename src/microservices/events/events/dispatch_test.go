@@ -0,0 +1,57 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDispatcherDispatch(t *testing.T) {
+	d := NewDispatcher()
+	var got CloudEvent
+	if err := d.Register(`^com\.cinimaabyss\.movie\.`, func(evt CloudEvent) error {
+		got = evt
+		return nil
+	}); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	evt := CloudEvent{ID: "evt-1", Type: TypeMovieViewed}
+	if err := d.Dispatch(evt); err != nil {
+		t.Fatalf("Dispatch returned error: %v", err)
+	}
+	if got.ID != evt.ID {
+		t.Errorf("handler received ID %q, want %q", got.ID, evt.ID)
+	}
+}
+
+func TestDispatcherNoHandlerMatched(t *testing.T) {
+	d := NewDispatcher()
+	if err := d.Register(`^com\.cinimaabyss\.movie\.`, func(CloudEvent) error { return nil }); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	err := d.Dispatch(CloudEvent{Type: TypeUser})
+	if err == nil {
+		t.Fatal("Dispatch with no matching handler: expected error, got nil")
+	}
+}
+
+func TestDispatcherPropagatesHandlerError(t *testing.T) {
+	d := NewDispatcher()
+	wantErr := errors.New("boom")
+	if err := d.Register(`^com\.cinimaabyss\.movie\.`, func(CloudEvent) error { return wantErr }); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+
+	err := d.Dispatch(CloudEvent{Type: TypeMovieViewed})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Dispatch error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestDispatcherRegisterInvalidPattern(t *testing.T) {
+	d := NewDispatcher()
+	if err := d.Register("(", func(CloudEvent) error { return nil }); err == nil {
+		t.Error("Register with invalid regex: expected error, got nil")
+	}
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"os"
+	"strings"
+)
+
+// TopicMapping resolves a CloudEvent type to the Kafka topic it should be
+// published to or consumed from.
+type TopicMapping struct {
+	routes       map[string]string
+	defaultTopic string
+}
+
+// NewTopicMapping builds a TopicMapping from routes, falling back to
+// defaultTopic for any type not explicitly listed.
+func NewTopicMapping(routes map[string]string, defaultTopic string) TopicMapping {
+	return TopicMapping{routes: routes, defaultTopic: defaultTopic}
+}
+
+// Topic returns the Kafka topic for the given CloudEvent type.
+func (m TopicMapping) Topic(eventType string) string {
+	if topic, ok := m.routes[eventType]; ok {
+		return topic
+	}
+	return m.defaultTopic
+}
+
+// DefaultTopicMapping builds the mapping this service uses in production:
+// TOPIC_MAPPING is a comma-separated list of "type=topic" pairs, and
+// DEFAULT_TOPIC is used for any type it doesn't cover. Both fall back to
+// this service's historical topic names when unset.
+func DefaultTopicMapping() TopicMapping {
+	routes := map[string]string{
+		TypeMovieViewed: "movie-events",
+		TypeMovieRated:  "movie-events",
+		TypeMovieAdded:  "movie-events",
+		TypeMovieOther:  "movie-events",
+		TypeUser:        "user-events",
+		TypePayment:     "payment-events",
+	}
+
+	if raw := os.Getenv("TOPIC_MAPPING"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			routes[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	defaultTopic := os.Getenv("DEFAULT_TOPIC")
+	if defaultTopic == "" {
+		defaultTopic = "movie-events"
+	}
+
+	return NewTopicMapping(routes, defaultTopic)
+}
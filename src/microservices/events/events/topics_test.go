@@ -0,0 +1,41 @@
+package events
+
+import "testing"
+
+func TestTopicMapping(t *testing.T) {
+	m := NewTopicMapping(map[string]string{
+		TypeUser: "user-events",
+	}, "default-events")
+
+	if got := m.Topic(TypeUser); got != "user-events" {
+		t.Errorf("Topic(%q) = %q, want user-events", TypeUser, got)
+	}
+	if got := m.Topic("com.cinimaabyss.unknown.v1"); got != "default-events" {
+		t.Errorf("Topic(unknown) = %q, want default-events", got)
+	}
+}
+
+func TestDefaultTopicMappingEnvOverride(t *testing.T) {
+	t.Setenv("TOPIC_MAPPING", "com.cinimaabyss.user.v1=custom-user-events,malformed")
+	t.Setenv("DEFAULT_TOPIC", "custom-default")
+
+	m := DefaultTopicMapping()
+
+	if got := m.Topic(TypeUser); got != "custom-user-events" {
+		t.Errorf("Topic(%q) = %q, want custom-user-events", TypeUser, got)
+	}
+	if got := m.Topic("com.cinimaabyss.unknown.v1"); got != "custom-default" {
+		t.Errorf("Topic(unknown) = %q, want custom-default", got)
+	}
+}
+
+func TestDefaultTopicMappingDefaults(t *testing.T) {
+	m := DefaultTopicMapping()
+
+	if got := m.Topic(TypeMovieViewed); got != "movie-events" {
+		t.Errorf("Topic(%q) = %q, want movie-events", TypeMovieViewed, got)
+	}
+	if got := m.Topic(TypePayment); got != "payment-events" {
+		t.Errorf("Topic(%q) = %q, want payment-events", TypePayment, got)
+	}
+}
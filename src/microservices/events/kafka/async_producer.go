@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"cinimaabyss/src/microservices/events/logging"
+	"cinimaabyss/src/microservices/events/metrics"
+)
+
+// NewAsyncProducer builds the AsyncProducer this service uses for
+// high-throughput publishing: Snappy compression plus batched flushing
+// tuned by env, so a handler request doesn't block on a broker round trip.
+func NewAsyncProducer() (sarama.AsyncProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+	config.Producer.Compression = sarama.CompressionSnappy
+	config.Producer.Flush.Frequency = flushFrequency()
+	config.Producer.Flush.MaxMessages = flushMaxMessages()
+
+	if err := ApplySecurity(config); err != nil {
+		return nil, fmt.Errorf("configure kafka security: %w", err)
+	}
+
+	producer, err := sarama.NewAsyncProducer(Brokers(), config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka async producer: %w", err)
+	}
+	return producer, nil
+}
+
+// flushFrequency is how often batched messages are flushed, configurable
+// via PRODUCER_FLUSH_FREQUENCY (a Go duration string, default 500ms).
+func flushFrequency() time.Duration {
+	if v := os.Getenv("PRODUCER_FLUSH_FREQUENCY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return 500 * time.Millisecond
+}
+
+// flushMaxMessages caps how many messages accumulate before a flush,
+// configurable via PRODUCER_FLUSH_MAX_MESSAGES (default 100).
+func flushMaxMessages() int {
+	if v := os.Getenv("PRODUCER_FLUSH_MAX_MESSAGES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 100
+}
+
+// AsyncPublishTimeout bounds how long a handler will block trying to enqueue
+// a message on the AsyncProducer's Input() channel, configurable via
+// ASYNC_PUBLISH_TIMEOUT (a Go duration string, default 5s). Input() is a
+// bounded channel, so if Kafka is down or slow long enough to fill it, a
+// publish must give up and fail the request rather than block forever.
+func AsyncPublishTimeout() time.Duration {
+	if v := os.Getenv("ASYNC_PUBLISH_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return 5 * time.Second
+}
+
+// WatchAsyncProducer drains producer's Successes/Errors channels in the
+// background until AsyncClose closes them, so publish failures are logged
+// instead of silently dropped.
+func WatchAsyncProducer(producer sarama.AsyncProducer) {
+	go func() {
+		for msg := range producer.Successes() {
+			logging.Logger.Debug().
+				Str("topic", msg.Topic).
+				Int32("partition", msg.Partition).
+				Int64("offset", msg.Offset).
+				Msg("async publish succeeded")
+		}
+	}()
+	go func() {
+		for err := range producer.Errors() {
+			metrics.KafkaPublishErrorsTotal.WithLabelValues(err.Msg.Topic).Inc()
+			logging.Logger.Error().Str("topic", err.Msg.Topic).Err(err.Err).Msg("async publish failed")
+		}
+	}()
+}
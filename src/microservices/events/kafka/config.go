@@ -0,0 +1,31 @@
+// Package kafka wires up this service's Sarama producer and consumer group,
+// keeping broker configuration and connection setup out of main.go.
+package kafka
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Brokers returns the broker list from KAFKA_BROKERS (comma separated),
+// falling back to the in-cluster default.
+func Brokers() []string {
+	raw := os.Getenv("KAFKA_BROKERS")
+	if raw == "" {
+		return []string{"kafka:9092"}
+	}
+	return strings.Split(raw, ",")
+}
+
+// ReplayTimeout bounds how long a single /api/events/dlq/{topic}/replay
+// request may run, configurable via REPLAY_TIMEOUT (a Go duration string,
+// default 30s).
+func ReplayTimeout() time.Duration {
+	if v := os.Getenv("REPLAY_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrokers(t *testing.T) {
+	if got := Brokers(); len(got) != 1 || got[0] != "kafka:9092" {
+		t.Errorf("Brokers() with KAFKA_BROKERS unset = %v, want [kafka:9092]", got)
+	}
+
+	t.Setenv("KAFKA_BROKERS", "broker-a:9092,broker-b:9092")
+	want := []string{"broker-a:9092", "broker-b:9092"}
+	got := Brokers()
+	if len(got) != len(want) {
+		t.Fatalf("Brokers() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Brokers()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReplayTimeout(t *testing.T) {
+	if got := ReplayTimeout(); got != 30*time.Second {
+		t.Errorf("ReplayTimeout() with REPLAY_TIMEOUT unset = %v, want 30s", got)
+	}
+
+	t.Setenv("REPLAY_TIMEOUT", "5s")
+	if got := ReplayTimeout(); got != 5*time.Second {
+		t.Errorf("ReplayTimeout() = %v, want 5s", got)
+	}
+
+	t.Setenv("REPLAY_TIMEOUT", "not-a-duration")
+	if got := ReplayTimeout(); got != 30*time.Second {
+		t.Errorf("ReplayTimeout() with malformed REPLAY_TIMEOUT = %v, want 30s fallback", got)
+	}
+}
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+
+	"cinimaabyss/src/microservices/events/logging"
+	"cinimaabyss/src/microservices/events/metrics"
+)
+
+// MessageHandler processes a single Kafka message's value. Returning an
+// error leaves the message's offset uncommitted so it is redelivered on the
+// next rebalance. ctx is the consumer-group session's context, cancelled as
+// soon as shutdown begins, so long-running handlers (e.g. retry backoff)
+// can exit early instead of blocking the drain.
+type MessageHandler func(ctx context.Context, msg *sarama.ConsumerMessage) error
+
+// GroupID returns the consumer group id for topic, honoring a
+// CONSUMER_GROUP_<TOPIC> override (dashes upper-cased to underscores),
+// falling back to CONSUMER_GROUP, then a per-topic default, so each topic's
+// consumer group rebalances independently unless an operator explicitly
+// opts into sharing one group across topics.
+func GroupID(topic string) string {
+	key := "CONSUMER_GROUP_" + strings.ToUpper(strings.ReplaceAll(topic, "-", "_"))
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	if v := os.Getenv("CONSUMER_GROUP"); v != "" {
+		return v
+	}
+	return "events-service-" + topic
+}
+
+// groupHandler adapts a MessageHandler to sarama.ConsumerGroupHandler,
+// committing each message's offset only after handle succeeds.
+type groupHandler struct {
+	handle MessageHandler
+}
+
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *groupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := h.handle(sess.Context(), msg); err != nil {
+				logging.Logger.Error().
+					Str("topic", msg.Topic).
+					Int32("partition", msg.Partition).
+					Int64("offset", msg.Offset).
+					Err(err).
+					Msg("error processing message")
+				// End the session rather than move on: marking a later
+				// message would commit past this one.
+				return nil
+			}
+			sess.MarkMessage(msg, "")
+			metrics.MessagesProcessedTotal.WithLabelValues(msg.Topic).Inc()
+			lag := claim.HighWaterMarkOffset() - msg.Offset - 1
+			metrics.ConsumerLag.WithLabelValues(msg.Topic, strconv.Itoa(int(msg.Partition))).Set(float64(lag))
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+}
+
+// Consume joins the consumer group for topic and blocks, re-joining after
+// every session (Sarama ends a session on each rebalance) until ctx is
+// cancelled.
+func Consume(ctx context.Context, topic string, handle MessageHandler) error {
+	config := sarama.NewConfig()
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+
+	if err := ApplySecurity(config); err != nil {
+		return fmt.Errorf("configure kafka security: %w", err)
+	}
+
+	group, err := sarama.NewConsumerGroup(Brokers(), GroupID(topic), config)
+	if err != nil {
+		return fmt.Errorf("create consumer group for topic %s: %w", topic, err)
+	}
+	defer group.Close()
+
+	handler := &groupHandler{handle: handle}
+
+	go func() {
+		for err := range group.Errors() {
+			logging.Logger.Error().Str("topic", topic).Err(err).Msg("consumer group error")
+		}
+	}()
+
+	for ctx.Err() == nil {
+		if err := group.Consume(ctx, []string{topic}, handler); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("consume topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
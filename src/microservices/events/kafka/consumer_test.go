@@ -0,0 +1,22 @@
+package kafka
+
+import "testing"
+
+func TestGroupID(t *testing.T) {
+	if got := GroupID("movie-events"); got != "events-service-movie-events" {
+		t.Errorf("GroupID(movie-events) with nothing set = %q, want events-service-movie-events", got)
+	}
+	if got := GroupID("user-events"); got != "events-service-user-events" {
+		t.Errorf("GroupID(user-events) with nothing set = %q, want events-service-user-events", got)
+	}
+
+	t.Setenv("CONSUMER_GROUP", "shared-group")
+	if got := GroupID("movie-events"); got != "shared-group" {
+		t.Errorf("GroupID() with CONSUMER_GROUP set = %q, want shared-group", got)
+	}
+
+	t.Setenv("CONSUMER_GROUP_MOVIE_EVENTS", "movie-group")
+	if got := GroupID("movie-events"); got != "movie-group" {
+		t.Errorf("GroupID() with per-topic override = %q, want movie-group", got)
+	}
+}
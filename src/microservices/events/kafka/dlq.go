@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+
+	"cinimaabyss/src/microservices/events/logging"
+	"cinimaabyss/src/microservices/events/metrics"
+)
+
+// DLQTopic returns the dead-letter topic for topic, honoring a
+// DLQ_TOPIC_<TOPIC> override (dashes upper-cased to underscores) and
+// otherwise suffixing topic with ".dlq".
+func DLQTopic(topic string) string {
+	key := "DLQ_TOPIC_" + strings.ToUpper(strings.ReplaceAll(topic, "-", "_"))
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return topic + ".dlq"
+}
+
+// MaxAttempts is how many times a message is attempted, the first try
+// included, before it is sent to its dead-letter topic, configurable via
+// MAX_PROCESS_ATTEMPTS (default 3).
+func MaxAttempts() int {
+	if v := os.Getenv("MAX_PROCESS_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 3
+}
+
+// RetryBackoff is the base exponential backoff delay between retries,
+// configurable via RETRY_BACKOFF (a Go duration string, default 200ms).
+func RetryBackoff() time.Duration {
+	if v := os.Getenv("RETRY_BACKOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return 200 * time.Millisecond
+}
+
+// WithDeadLetter wraps handle with bounded retries and exponential backoff.
+// If every attempt fails, the original message is republished to its
+// dead-letter topic with headers carrying the failure context, so a bad
+// message can't block the partition forever. The backoff sleep is
+// cancelled alongside ctx so a SIGTERM drains the consumer group promptly
+// instead of waiting out whatever RETRY_BACKOFF/MAX_PROCESS_ATTEMPTS happen
+// to be configured.
+func WithDeadLetter(producer sarama.SyncProducer, handle MessageHandler) MessageHandler {
+	maxAttempts := MaxAttempts()
+	backoff := RetryBackoff()
+
+	return func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		var err error
+		for attempt := 1; attempt <= maxAttempts; attempt++ {
+			if err = handle(ctx, msg); err == nil {
+				return nil
+			}
+			if attempt < maxAttempts {
+				select {
+				case <-time.After(backoff * time.Duration(uint(1)<<uint(attempt-1))):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+		return publishToDLQ(producer, msg, err, maxAttempts)
+	}
+}
+
+func publishToDLQ(producer sarama.SyncProducer, msg *sarama.ConsumerMessage, cause error, attempts int) error {
+	dlqTopic := DLQTopic(msg.Topic)
+	dlqMsg := &sarama.ProducerMessage{
+		Topic: dlqTopic,
+		Key:   sarama.ByteEncoder(msg.Key),
+		Value: sarama.ByteEncoder(msg.Value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("x-original-topic"), Value: []byte(msg.Topic)},
+			{Key: []byte("x-original-partition"), Value: []byte(strconv.Itoa(int(msg.Partition)))},
+			{Key: []byte("x-original-offset"), Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+			{Key: []byte("x-error"), Value: []byte(cause.Error())},
+			{Key: []byte("x-retry-count"), Value: []byte(strconv.Itoa(attempts))},
+		},
+	}
+	if _, _, err := producer.SendMessage(dlqMsg); err != nil {
+		return fmt.Errorf("publish to dlq topic %s: %w", dlqTopic, err)
+	}
+	metrics.DLQPushesTotal.WithLabelValues(msg.Topic).Inc()
+	logging.Logger.Warn().
+		Str("topic", msg.Topic).
+		Int32("partition", msg.Partition).
+		Int64("offset", msg.Offset).
+		Int("retry_count", attempts).
+		Err(cause).
+		Msg("message sent to dead-letter topic")
+	return nil
+}
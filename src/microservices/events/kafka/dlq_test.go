@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+)
+
+func TestDLQTopic(t *testing.T) {
+	if got := DLQTopic("movie-events"); got != "movie-events.dlq" {
+		t.Errorf("DLQTopic(movie-events) = %q, want movie-events.dlq", got)
+	}
+
+	t.Setenv("DLQ_TOPIC_MOVIE_EVENTS", "movie-events-dead-letters")
+	if got := DLQTopic("movie-events"); got != "movie-events-dead-letters" {
+		t.Errorf("DLQTopic(movie-events) with override = %q, want movie-events-dead-letters", got)
+	}
+}
+
+func TestMaxAttempts(t *testing.T) {
+	if got := MaxAttempts(); got != 3 {
+		t.Errorf("MaxAttempts() with nothing set = %d, want 3", got)
+	}
+
+	t.Setenv("MAX_PROCESS_ATTEMPTS", "5")
+	if got := MaxAttempts(); got != 5 {
+		t.Errorf("MaxAttempts() = %d, want 5", got)
+	}
+
+	t.Setenv("MAX_PROCESS_ATTEMPTS", "0")
+	if got := MaxAttempts(); got != 3 {
+		t.Errorf("MaxAttempts() with non-positive override = %d, want 3 fallback", got)
+	}
+
+	t.Setenv("MAX_PROCESS_ATTEMPTS", "not-a-number")
+	if got := MaxAttempts(); got != 3 {
+		t.Errorf("MaxAttempts() with malformed override = %d, want 3 fallback", got)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	if got := RetryBackoff(); got != 200*time.Millisecond {
+		t.Errorf("RetryBackoff() with nothing set = %v, want 200ms", got)
+	}
+
+	t.Setenv("RETRY_BACKOFF", "1s")
+	if got := RetryBackoff(); got != time.Second {
+		t.Errorf("RetryBackoff() = %v, want 1s", got)
+	}
+
+	t.Setenv("RETRY_BACKOFF", "not-a-duration")
+	if got := RetryBackoff(); got != 200*time.Millisecond {
+		t.Errorf("RetryBackoff() with malformed override = %v, want 200ms fallback", got)
+	}
+}
+
+func TestWithDeadLetterSucceedsWithoutPublishing(t *testing.T) {
+	t.Setenv("MAX_PROCESS_ATTEMPTS", "3")
+	t.Setenv("RETRY_BACKOFF", "1ms")
+
+	producer := mocks.NewSyncProducer(t, nil)
+	defer producer.Close()
+
+	calls := 0
+	handle := WithDeadLetter(producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		calls++
+		return nil
+	})
+
+	msg := &sarama.ConsumerMessage{Topic: "movie-events"}
+	if err := handle(context.Background(), msg); err != nil {
+		t.Fatalf("handle returned error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestWithDeadLetterPublishesAfterExhaustingRetries(t *testing.T) {
+	t.Setenv("MAX_PROCESS_ATTEMPTS", "2")
+	t.Setenv("RETRY_BACKOFF", "1ms")
+
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageAndSucceed()
+	defer producer.Close()
+
+	calls := 0
+	wantErr := errors.New("boom")
+	handle := WithDeadLetter(producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		calls++
+		return wantErr
+	})
+
+	msg := &sarama.ConsumerMessage{Topic: "movie-events"}
+	if err := handle(context.Background(), msg); err != nil {
+		t.Fatalf("handle returned error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2 (MAX_PROCESS_ATTEMPTS)", calls)
+	}
+}
+
+func TestWithDeadLetterCancelsDuringBackoff(t *testing.T) {
+	t.Setenv("MAX_PROCESS_ATTEMPTS", "5")
+	t.Setenv("RETRY_BACKOFF", "1h")
+
+	producer := mocks.NewSyncProducer(t, nil)
+	defer producer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := WithDeadLetter(producer, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		return errors.New("boom")
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- handle(ctx, &sarama.ConsumerMessage{Topic: "movie-events"}) }()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handle returned %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handle did not return after context cancellation")
+	}
+}
@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// NewProducer builds the SyncProducer this service uses to publish events.
+//
+// Messages are hash-partitioned on their key (see handleMovieEvent and
+// friends in main.go), so all events for one entity land on the same
+// partition and are read in order by a single consumer-group member. That
+// only holds if a topic's partition count stays fixed once it's created —
+// adding partitions later reshuffles the key-to-partition mapping and
+// breaks ordering for keys already in flight.
+func NewProducer() (sarama.SyncProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 5
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+
+	if err := ApplySecurity(config); err != nil {
+		return nil, fmt.Errorf("configure kafka security: %w", err)
+	}
+
+	producer, err := sarama.NewSyncProducer(Brokers(), config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka producer: %w", err)
+	}
+	return producer, nil
+}
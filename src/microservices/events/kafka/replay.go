@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Shopify/sarama"
+)
+
+// Replay re-consumes every message currently on topic's dead-letter queue
+// and republishes it to topic, returning how many messages were replayed.
+// It is meant for operators to recover after fixing the bug that sent
+// messages to the DLQ in the first place. It stops and returns early if ctx
+// is cancelled, instead of blocking forever on a partition that never
+// delivers as many messages as the offset range implied.
+func Replay(ctx context.Context, producer sarama.SyncProducer, topic string) (int, error) {
+	dlqTopic := DLQTopic(topic)
+
+	config := sarama.NewConfig()
+	if err := ApplySecurity(config); err != nil {
+		return 0, fmt.Errorf("configure kafka security: %w", err)
+	}
+
+	client, err := sarama.NewClient(Brokers(), config)
+	if err != nil {
+		return 0, fmt.Errorf("create replay client: %w", err)
+	}
+	defer client.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, fmt.Errorf("create replay consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	partitions, err := client.Partitions(dlqTopic)
+	if err != nil {
+		return 0, fmt.Errorf("list partitions for %s: %w", dlqTopic, err)
+	}
+
+	replayed := 0
+	for _, partition := range partitions {
+		oldest, err := client.GetOffset(dlqTopic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return replayed, fmt.Errorf("get oldest offset for %s/%d: %w", dlqTopic, partition, err)
+		}
+		newest, err := client.GetOffset(dlqTopic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return replayed, fmt.Errorf("get newest offset for %s/%d: %w", dlqTopic, partition, err)
+		}
+		if newest <= oldest {
+			continue
+		}
+
+		n, err := replayPartition(ctx, producer, consumer, dlqTopic, topic, partition, oldest, newest)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+	}
+
+	return replayed, nil
+}
+
+func replayPartition(ctx context.Context, producer sarama.SyncProducer, consumer sarama.Consumer, dlqTopic, originalTopic string, partition int32, oldest, newest int64) (int, error) {
+	pc, err := consumer.ConsumePartition(dlqTopic, partition, oldest)
+	if err != nil {
+		return 0, fmt.Errorf("consume %s/%d: %w", dlqTopic, partition, err)
+	}
+	defer pc.Close()
+
+	replayed := 0
+	for offset := oldest; offset < newest; offset++ {
+		select {
+		case msg := <-pc.Messages():
+			republish := &sarama.ProducerMessage{
+				Topic: originalTopic,
+				Key:   sarama.ByteEncoder(msg.Key),
+				Value: sarama.ByteEncoder(msg.Value),
+			}
+			if _, _, err := producer.SendMessage(republish); err != nil {
+				return replayed, fmt.Errorf("republish message from %s: %w", dlqTopic, err)
+			}
+			replayed++
+		case err := <-pc.Errors():
+			return replayed, fmt.Errorf("consume %s/%d: %w", dlqTopic, partition, err)
+		case <-ctx.Done():
+			return replayed, fmt.Errorf("replay of %s/%d timed out after %d messages: %w", dlqTopic, partition, replayed, ctx.Err())
+		}
+	}
+	return replayed, nil
+}
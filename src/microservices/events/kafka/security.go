@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// ApplySecurity configures SASL and TLS on config from environment
+// variables, so the same producer/consumer setup works whether brokers are
+// plaintext or require auth, as managed offerings like AWS MSK or
+// Confluent Cloud do.
+func ApplySecurity(config *sarama.Config) error {
+	if err := applySASL(config); err != nil {
+		return err
+	}
+	return applyTLS(config)
+}
+
+// applySASL reads KAFKA_SASL_MECHANISM (PLAIN, SCRAM-SHA-256,
+// SCRAM-SHA-512), KAFKA_SASL_USER, and KAFKA_SASL_PASSWORD. It is a no-op
+// when KAFKA_SASL_MECHANISM is unset.
+func applySASL(config *sarama.Config) error {
+	mechanism := os.Getenv("KAFKA_SASL_MECHANISM")
+	if mechanism == "" {
+		return nil
+	}
+
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = os.Getenv("KAFKA_SASL_USER")
+	config.Net.SASL.Password = os.Getenv("KAFKA_SASL_PASSWORD")
+
+	switch mechanism {
+	case "PLAIN":
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case "SCRAM-SHA-256":
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGeneratorFcn: scram.SHA256}
+		}
+	case "SCRAM-SHA-512":
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGeneratorFcn: scram.SHA512}
+		}
+	default:
+		return fmt.Errorf("unsupported KAFKA_SASL_MECHANISM %q", mechanism)
+	}
+	return nil
+}
+
+// applyTLS reads KAFKA_TLS_ENABLE, KAFKA_TLS_CA_PATH,
+// KAFKA_TLS_CERT_PATH/KAFKA_TLS_KEY_PATH, and
+// KAFKA_TLS_INSECURE_SKIP_VERIFY. It is a no-op unless KAFKA_TLS_ENABLE is
+// "true".
+func applyTLS(config *sarama.Config) error {
+	if os.Getenv("KAFKA_TLS_ENABLE") != "true" {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: os.Getenv("KAFKA_TLS_INSECURE_SKIP_VERIFY") == "true",
+	}
+
+	if caPath := os.Getenv("KAFKA_TLS_CA_PATH"); caPath != "" {
+		caCert, err := os.ReadFile(caPath)
+		if err != nil {
+			return fmt.Errorf("read KAFKA_TLS_CA_PATH: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("no certificates parsed from KAFKA_TLS_CA_PATH")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certPath := os.Getenv("KAFKA_TLS_CERT_PATH")
+	keyPath := os.Getenv("KAFKA_TLS_KEY_PATH")
+	if certPath != "" && keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return fmt.Errorf("load KAFKA_TLS_CERT_PATH/KAFKA_TLS_KEY_PATH: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	config.Net.TLS.Enable = true
+	config.Net.TLS.Config = tlsConfig
+	return nil
+}
+
+// scramClient adapts xdg-go/scram to sarama.SCRAMClient so SASL/SCRAM
+// handshakes work against brokers that require them.
+type scramClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	hashGeneratorFcn scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return fmt.Errorf("create scram client: %w", err)
+	}
+	c.Client = client
+	c.ClientConversation = c.Client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.ClientConversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.ClientConversation.Done()
+}
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestApplySASL(t *testing.T) {
+	cases := []struct {
+		name      string
+		mechanism string
+		wantErr   bool
+	}{
+		{name: "unset is a no-op", mechanism: ""},
+		{name: "PLAIN", mechanism: "PLAIN"},
+		{name: "SCRAM-SHA-256", mechanism: "SCRAM-SHA-256"},
+		{name: "SCRAM-SHA-512", mechanism: "SCRAM-SHA-512"},
+		{name: "unsupported mechanism", mechanism: "GSSAPI", wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("KAFKA_SASL_MECHANISM", c.mechanism)
+
+			config := sarama.NewConfig()
+			err := applySASL(config)
+
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("applySASL() with mechanism %q: expected error, got nil", c.mechanism)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applySASL() with mechanism %q returned error: %v", c.mechanism, err)
+			}
+			if c.mechanism == "" {
+				if config.Net.SASL.Enable {
+					t.Error("applySASL() with KAFKA_SASL_MECHANISM unset enabled SASL")
+				}
+				return
+			}
+			if !config.Net.SASL.Enable {
+				t.Error("applySASL() did not enable SASL")
+			}
+		})
+	}
+}
+
+func TestApplyTLSDisabledByDefault(t *testing.T) {
+	config := sarama.NewConfig()
+	if err := applyTLS(config); err != nil {
+		t.Fatalf("applyTLS() with KAFKA_TLS_ENABLE unset returned error: %v", err)
+	}
+	if config.Net.TLS.Enable {
+		t.Error("applyTLS() with KAFKA_TLS_ENABLE unset enabled TLS")
+	}
+}
+
+func TestApplyTLSInvalidCAFile(t *testing.T) {
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caPath, []byte("not a pem file"), 0o600); err != nil {
+		t.Fatalf("write test CA file: %v", err)
+	}
+
+	t.Setenv("KAFKA_TLS_ENABLE", "true")
+	t.Setenv("KAFKA_TLS_CA_PATH", caPath)
+
+	config := sarama.NewConfig()
+	if err := applyTLS(config); err == nil {
+		t.Error("applyTLS() with a non-PEM KAFKA_TLS_CA_PATH: expected error, got nil")
+	}
+}
+
+func TestApplyTLSMissingCAFile(t *testing.T) {
+	t.Setenv("KAFKA_TLS_ENABLE", "true")
+	t.Setenv("KAFKA_TLS_CA_PATH", filepath.Join(t.TempDir(), "does-not-exist.pem"))
+
+	config := sarama.NewConfig()
+	if err := applyTLS(config); err == nil {
+		t.Error("applyTLS() with a missing KAFKA_TLS_CA_PATH: expected error, got nil")
+	}
+}
@@ -0,0 +1,28 @@
+// Package logging configures the structured logger shared across the
+// events service, so log lines from the HTTP and Kafka produce/consume
+// paths can be correlated by topic, partition, offset, and event id/type
+// in a log aggregator.
+package logging
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// Logger is the service-wide structured logger. It writes JSON to stdout;
+// LOG_LEVEL (debug, info, warn, error) controls verbosity and defaults to
+// info.
+var Logger = zerolog.New(os.Stdout).With().Timestamp().Logger().Level(level())
+
+func level() zerolog.Level {
+	raw := os.Getenv("LOG_LEVEL")
+	if raw == "" {
+		return zerolog.InfoLevel
+	}
+	lvl, err := zerolog.ParseLevel(raw)
+	if err != nil || lvl == zerolog.NoLevel {
+		return zerolog.InfoLevel
+	}
+	return lvl
+}
@@ -1,24 +1,30 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+
+	"cinimaabyss/src/microservices/events/events"
+	"cinimaabyss/src/microservices/events/kafka"
+	"cinimaabyss/src/microservices/events/logging"
+	"cinimaabyss/src/microservices/events/metrics"
 )
 
-// Event represents a generic event in the system
-type Event struct {
-	ID        string      `json:"id"`
-	Type      string      `json:"type"`
-	Timestamp time.Time   `json:"timestamp"`
-	Payload   interface{} `json:"payload"`
-}
+var log = logging.Logger
 
 // MovieEvent represents a movie-related event
 type MovieEvent struct {
@@ -29,45 +35,84 @@ type MovieEvent struct {
 	Rating      float64  `json:"rating,omitempty"`
 	Genres      []string `json:"genres,omitempty"`
 	Description string   `json:"description,omitempty"`
+	// Key overrides the Kafka partitioning key. When empty, callers get the
+	// default of MovieID so a movie's events stay ordered.
+	Key string `json:"key,omitempty"`
 }
 
 // UserEvent represents a user-related event
 type UserEvent struct {
-	UserID    int       `json:"user_id"`
-	Username  string    `json:"username,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Action    string    `json:"action"` // registered, logged_in, updated_profile, etc.
-	Timestamp time.Time `json:"timestamp"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Action   string `json:"action"` // registered, logged_in, updated_profile, etc.
+	// Key overrides the Kafka partitioning key. When empty, callers get the
+	// default of UserID so a user's events stay ordered.
+	Key string `json:"key,omitempty"`
 }
 
 // PaymentEvent represents a payment-related event
 type PaymentEvent struct {
-	PaymentID  int       `json:"payment_id"`
-	UserID     int       `json:"user_id"`
-	Amount     float64   `json:"amount"`
-	Status     string    `json:"status"` // completed, failed, refunded, etc.
-	Timestamp  time.Time `json:"timestamp"`
-	MethodType string    `json:"method_type,omitempty"`
+	PaymentID  int     `json:"payment_id"`
+	UserID     int     `json:"user_id"`
+	Amount     float64 `json:"amount"`
+	Status     string  `json:"status"` // completed, failed, refunded, etc.
+	MethodType string  `json:"method_type,omitempty"`
+	// Key overrides the Kafka partitioning key. When empty, callers get the
+	// default of UserID so one user's payments stay ordered relative to
+	// each other, matching UserEvent's default.
+	Key string `json:"key,omitempty"`
 }
 
 var (
-	producer sarama.SyncProducer
-	consumer sarama.Consumer
+	producer      sarama.SyncProducer
+	asyncProducer sarama.AsyncProducer
+	topics        events.TopicMapping
+	dispatch      *events.Dispatcher
+
+	// asyncPublishWG tracks handlers currently parked on asyncProducer.Input(),
+	// so shutdown can wait for them to finish before closing the producer
+	// instead of risking a send on its closed input channel.
+	asyncPublishWG sync.WaitGroup
 )
 
 func main() {
-	// Initialize Kafka producer
-	initKafkaProducer()
-	defer producer.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Initialize Kafka consumer
-	initKafkaConsumer()
-	defer consumer.Close()
+	topics = events.DefaultTopicMapping()
+	dispatch = newDispatcher()
 
-	// Start consuming messages in the background
-	go consumeMessages("movie-events")
-	go consumeMessages("user-events")
-	go consumeMessages("payment-events")
+	// Initialize Kafka producers: a SyncProducer for ?ack=sync callers and
+	// admin paths (DLQ replay), and the default AsyncProducer for
+	// high-throughput publishing.
+	var err error
+	producer, err = kafka.NewProducer()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create Kafka producer")
+	}
+	log.Info().Msg("Kafka producer initialized successfully")
+
+	asyncProducer, err = kafka.NewAsyncProducer()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to create Kafka async producer")
+	}
+	kafka.WatchAsyncProducer(asyncProducer)
+	log.Info().Msg("Kafka async producer initialized successfully")
+
+	// Start consumer groups in the background; they drain when ctx is
+	// cancelled on SIGINT/SIGTERM.
+	var consumerWG sync.WaitGroup
+	for _, topic := range []string{"movie-events", "user-events", "payment-events"} {
+		consumerWG.Add(1)
+		go func(topic string) {
+			defer consumerWG.Done()
+			log.Info().Str("topic", topic).Msg("started consuming messages")
+			if err := kafka.Consume(ctx, topic, kafka.WithDeadLetter(producer, processMessage)); err != nil {
+				log.Error().Str("topic", topic).Err(err).Msg("consumer group stopped")
+			}
+		}(topic)
+	}
 
 	// Set up HTTP routes
 	router := mux.NewRouter()
@@ -75,111 +120,93 @@ func main() {
 	router.HandleFunc("/api/events/movie", handleMovieEvent).Methods("POST")
 	router.HandleFunc("/api/events/user", handleUserEvent).Methods("POST")
 	router.HandleFunc("/api/events/payment", handlePaymentEvent).Methods("POST")
+	router.HandleFunc("/api/events/dlq/{topic}/replay", handleReplayDLQ).Methods("POST")
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
 
-	// Start server
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8082"
 	}
-	log.Printf("Starting events service on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, router))
-}
-
-func initKafkaProducer() {
-	// Get Kafka broker address from environment variable or use default
-	brokers := []string{os.Getenv("KAFKA_BROKERS")}
-	if brokers[0] == "" {
-		brokers[0] = "kafka:9092"
-	}
-
-	// Create Kafka producer configuration
-	config := sarama.NewConfig()
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 5
-	config.Producer.Return.Successes = true
-
-	// Create Kafka producer
-	var err error
-	producer, err = sarama.NewSyncProducer(brokers, config)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %v", err)
-	}
+	server := &http.Server{Addr: ":" + port, Handler: router}
 
-	log.Println("Kafka producer initialized successfully")
-}
-
-func initKafkaConsumer() {
-	// Get Kafka broker address from environment variable or use default
-	brokers := []string{os.Getenv("KAFKA_BROKERS")}
-	if brokers[0] == "" {
-		brokers[0] = "kafka:9092"
-	}
+	go func() {
+		log.Info().Str("port", port).Msg("starting events service")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal().Err(err).Msg("HTTP server error")
+		}
+	}()
 
-	// Create Kafka consumer configuration
-	config := sarama.NewConfig()
-	config.Consumer.Return.Errors = true
+	<-ctx.Done()
+	log.Info().Msg("shutting down: draining HTTP server and consumer groups")
 
-	// Create Kafka consumer
-	var err error
-	consumer, err = sarama.NewConsumer(brokers, config)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %v", err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("HTTP server shutdown error")
 	}
 
-	log.Println("Kafka consumer initialized successfully")
-}
+	consumerWG.Wait()
+	asyncPublishWG.Wait()
 
-func consumeMessages(topic string) {
-	// Create a consumer for the specified topic
-	partitionConsumer, err := consumer.ConsumePartition(topic, 0, sarama.OffsetNewest)
-	if err != nil {
-		log.Printf("Failed to create partition consumer for topic %s: %v", topic, err)
-		return
+	if err := asyncProducer.Close(); err != nil {
+		log.Error().Err(err).Msg("async producer close error")
 	}
-	defer partitionConsumer.Close()
-
-	log.Printf("Started consuming messages from topic: %s", topic)
-
-	// Continuously consume messages
-	for {
-		select {
-		case msg := <-partitionConsumer.Messages():
-			log.Printf("Received message from topic %s: %s", topic, string(msg.Value))
-			processMessage(topic, msg.Value)
-		case err := <-partitionConsumer.Errors():
-			log.Printf("Error consuming from topic %s: %v", topic, err)
-		}
+	if err := producer.Close(); err != nil {
+		log.Error().Err(err).Msg("producer close error")
 	}
 }
 
-func processMessage(topic string, message []byte) {
-	// Process the message based on the topic
-	switch topic {
-	case "movie-events":
-		var event Event
-		if err := json.Unmarshal(message, &event); err != nil {
-			log.Printf("Error unmarshaling movie event: %v", err)
-			return
+// newDispatcher registers the handlers that process CloudEvents pulled off
+// each topic, keyed by a regex over the CloudEvent type.
+func newDispatcher() *events.Dispatcher {
+	d := events.NewDispatcher()
+	d.Register(`^com\.cinimaabyss\.movie\.`, func(evt events.CloudEvent) error {
+		var movieEvent MovieEvent
+		if err := evt.Unmarshal(&movieEvent); err != nil {
+			return err
 		}
-		log.Printf("Processing movie event: %+v", event)
+		eventLogger(evt).Info().Interface("payload", movieEvent).Msg("processing movie event")
 		// Implement business logic for movie events
-	case "user-events":
-		var event Event
-		if err := json.Unmarshal(message, &event); err != nil {
-			log.Printf("Error unmarshaling user event: %v", err)
-			return
+		return nil
+	})
+	d.Register(`^com\.cinimaabyss\.user\.`, func(evt events.CloudEvent) error {
+		var userEvent UserEvent
+		if err := evt.Unmarshal(&userEvent); err != nil {
+			return err
 		}
-		log.Printf("Processing user event: %+v", event)
+		eventLogger(evt).Info().Interface("payload", userEvent).Msg("processing user event")
 		// Implement business logic for user events
-	case "payment-events":
-		var event Event
-		if err := json.Unmarshal(message, &event); err != nil {
-			log.Printf("Error unmarshaling payment event: %v", err)
-			return
+		return nil
+	})
+	d.Register(`^com\.cinimaabyss\.payment\.`, func(evt events.CloudEvent) error {
+		var paymentEvent PaymentEvent
+		if err := evt.Unmarshal(&paymentEvent); err != nil {
+			return err
 		}
-		log.Printf("Processing payment event: %+v", event)
+		eventLogger(evt).Info().Interface("payload", paymentEvent).Msg("processing payment event")
 		// Implement business logic for payment events
+		return nil
+	})
+	return d
+}
+
+// processMessage decodes a CloudEvent off the wire and dispatches it by
+// type, regardless of which topic it arrived on. It is a kafka.MessageHandler:
+// returning an error leaves the message's offset uncommitted for redelivery.
+func processMessage(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	var evt events.CloudEvent
+	if err := json.Unmarshal(msg.Value, &evt); err != nil {
+		metrics.JSONDecodeFailuresTotal.WithLabelValues("cloudevent").Inc()
+		return fmt.Errorf("unmarshal cloudevent: %w", err)
 	}
+	return dispatch.Dispatch(evt)
+}
+
+// eventLogger returns a logger carrying the event_id and event_type fields
+// so a CloudEvent's processing can be correlated across log lines.
+func eventLogger(evt events.CloudEvent) *zerolog.Logger {
+	l := log.With().Str("event_id", evt.ID).Str("event_type", evt.Type).Logger()
+	return &l
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -190,134 +217,196 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 func handleMovieEvent(w http.ResponseWriter, r *http.Request) {
 	var movieEvent MovieEvent
 	if err := json.NewDecoder(r.Body).Decode(&movieEvent); err != nil {
+		metrics.JSONDecodeFailuresTotal.WithLabelValues("http_request").Inc()
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	metrics.HTTPEventsTotal.WithLabelValues("movie").Inc()
 
-	// Create a generic event with the movie event as payload
-	event := Event{
-		ID:        fmt.Sprintf("movie-%d-%s", movieEvent.MovieID, movieEvent.Action),
-		Type:      "movie",
-		Timestamp: time.Now(),
-		Payload:   movieEvent,
-	}
-
-	// Convert event to JSON
-	eventJSON, err := json.Marshal(event)
+	eventType := events.MovieEventType(movieEvent.Action)
+	subject := fmt.Sprintf("movie-%d", movieEvent.MovieID)
+	evt, err := events.New(eventType, subject, movieEvent)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Send event to Kafka
-	msg := &sarama.ProducerMessage{
-		Topic: "movie-events",
-		Value: sarama.StringEncoder(eventJSON),
+	key := movieEvent.Key
+	if key == "" {
+		key = strconv.Itoa(movieEvent.MovieID)
 	}
 
-	partition, offset, err := producer.SendMessage(msg)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	log.Printf("Movie event sent to partition %d at offset %d", partition, offset)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "success",
-		"partition": partition,
-		"offset":    offset,
-		"event":     event,
-	})
+	publishEvent(w, r, topics.Topic(eventType), key, evt)
 }
 
 func handleUserEvent(w http.ResponseWriter, r *http.Request) {
 	var userEvent UserEvent
 	if err := json.NewDecoder(r.Body).Decode(&userEvent); err != nil {
+		metrics.JSONDecodeFailuresTotal.WithLabelValues("http_request").Inc()
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	metrics.HTTPEventsTotal.WithLabelValues("user").Inc()
+
+	subject := fmt.Sprintf("user-%d", userEvent.UserID)
+	evt, err := events.New(events.TypeUser, subject, userEvent)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// Create a generic event with the user event as payload
-	event := Event{
-		ID:        fmt.Sprintf("user-%d-%s", userEvent.UserID, userEvent.Action),
-		Type:      "user",
-		Timestamp: time.Now(),
-		Payload:   userEvent,
+	key := userEvent.Key
+	if key == "" {
+		key = strconv.Itoa(userEvent.UserID)
+	}
+
+	publishEvent(w, r, topics.Topic(events.TypeUser), key, evt)
+}
+
+func handlePaymentEvent(w http.ResponseWriter, r *http.Request) {
+	var paymentEvent PaymentEvent
+	if err := json.NewDecoder(r.Body).Decode(&paymentEvent); err != nil {
+		metrics.JSONDecodeFailuresTotal.WithLabelValues("http_request").Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
+	metrics.HTTPEventsTotal.WithLabelValues("payment").Inc()
 
-	// Convert event to JSON
-	eventJSON, err := json.Marshal(event)
+	subject := fmt.Sprintf("payment-%d", paymentEvent.PaymentID)
+	evt, err := events.New(events.TypePayment, subject, paymentEvent)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Send event to Kafka
-	msg := &sarama.ProducerMessage{
-		Topic: "user-events",
-		Value: sarama.StringEncoder(eventJSON),
+	key := paymentEvent.Key
+	if key == "" {
+		key = strconv.Itoa(paymentEvent.UserID)
 	}
 
-	partition, offset, err := producer.SendMessage(msg)
+	publishEvent(w, r, topics.Topic(events.TypePayment), key, evt)
+}
+
+// handleReplayDLQ re-consumes a dead-letter topic and republishes its
+// messages to the original topic, so operators can recover after fixing
+// the bug that caused them to fail processing.
+func handleReplayDLQ(w http.ResponseWriter, r *http.Request) {
+	topic := mux.Vars(r)["topic"]
+
+	ctx, cancel := context.WithTimeout(r.Context(), kafka.ReplayTimeout())
+	defer cancel()
+
+	replayed, err := kafka.Replay(ctx, producer, topic)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("User event sent to partition %d at offset %d", partition, offset)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "success",
-		"partition": partition,
-		"offset":    offset,
-		"event":     event,
+		"status":   "success",
+		"topic":    topic,
+		"replayed": replayed,
 	})
 }
 
-func handlePaymentEvent(w http.ResponseWriter, r *http.Request) {
-	var paymentEvent PaymentEvent
-	if err := json.NewDecoder(r.Body).Decode(&paymentEvent); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// publishEvent marshals a CloudEvent, sends it to topic keyed by key so the
+// hash partitioner keeps one entity's events on a single partition, and
+// writes the HTTP response shared by all three producer endpoints. By
+// default it publishes through the batched AsyncProducer; callers that
+// need the partition/offset Kafka assigned can opt into the blocking
+// SyncProducer with ?ack=sync or an "X-Ack-Mode: sync" header.
+func publishEvent(w http.ResponseWriter, r *http.Request, topic, key string, evt events.CloudEvent) {
+	eventJSON, err := json.Marshal(evt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Create a generic event with the payment event as payload
-	event := Event{
-		ID:        fmt.Sprintf("payment-%d-%s", paymentEvent.PaymentID, paymentEvent.Status),
-		Type:      "payment",
-		Timestamp: time.Now(),
-		Payload:   paymentEvent,
+	if ackMode(r) == "sync" {
+		publishSync(w, topic, key, evt, eventJSON)
+		return
 	}
+	publishAsync(w, r, topic, key, evt, eventJSON)
+}
 
-	// Convert event to JSON
-	eventJSON, err := json.Marshal(event)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+// ackMode returns the acknowledgement mode the caller asked for, from the
+// ack query parameter or else the X-Ack-Mode header, defaulting to "async".
+// "sync" is needed when the response must carry the partition and offset
+// Kafka assigned to the message.
+func ackMode(r *http.Request) string {
+	if mode := r.URL.Query().Get("ack"); mode != "" {
+		return mode
+	}
+	if mode := r.Header.Get("X-Ack-Mode"); mode != "" {
+		return mode
 	}
+	return "async"
+}
 
-	// Send event to Kafka
+func publishSync(w http.ResponseWriter, topic, key string, evt events.CloudEvent, eventJSON []byte) {
 	msg := &sarama.ProducerMessage{
-		Topic: "payment-events",
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
 		Value: sarama.StringEncoder(eventJSON),
 	}
 
+	timer := prometheus.NewTimer(metrics.KafkaPublishDuration.WithLabelValues(topic))
 	partition, offset, err := producer.SendMessage(msg)
+	timer.ObserveDuration()
 	if err != nil {
+		metrics.KafkaPublishErrorsTotal.WithLabelValues(topic).Inc()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	log.Printf("Payment event sent to partition %d at offset %d", partition, offset)
+	eventLogger(evt).Info().
+		Str("topic", topic).
+		Int32("partition", partition).
+		Int64("offset", offset).
+		Msg("event sent")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":    "success",
 		"partition": partition,
 		"offset":    offset,
-		"event":     event,
+		"event":     evt,
+	})
+}
+
+// publishAsync enqueues msg on the AsyncProducer's Input() channel, bounded
+// by kafka.AsyncPublishTimeout() so a Kafka outage that fills the channel's
+// buffer fails this request with 503 instead of blocking the handler
+// goroutine forever. asyncPublishWG lets shutdown wait for any handler still
+// parked on the send before asyncProducer.Close() closes that channel out
+// from under it.
+func publishAsync(w http.ResponseWriter, r *http.Request, topic, key string, evt events.CloudEvent, eventJSON []byte) {
+	asyncPublishWG.Add(1)
+	defer asyncPublishWG.Done()
+
+	ctx, cancel := context.WithTimeout(r.Context(), kafka.AsyncPublishTimeout())
+	defer cancel()
+
+	msg := &sarama.ProducerMessage{
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.StringEncoder(eventJSON),
+	}
+
+	select {
+	case asyncProducer.Input() <- msg:
+	case <-ctx.Done():
+		metrics.KafkaPublishErrorsTotal.WithLabelValues(topic).Inc()
+		http.Error(w, "async publish queue is backed up, retry or use ?ack=sync", http.StatusServiceUnavailable)
+		return
+	}
+	eventLogger(evt).Debug().Str("topic", topic).Msg("event queued for async publish")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "accepted",
+		"event":  evt,
 	})
 }
@@ -0,0 +1,58 @@
+// Package metrics defines the Prometheus collectors the events service
+// exposes on /metrics, instrumenting the HTTP and Kafka produce/consume
+// paths so events can be correlated end-to-end across the movie, user, and
+// payment services in a dashboard.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPEventsTotal counts HTTP events accepted, by event type.
+	HTTPEventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_http_events_total",
+		Help: "HTTP events accepted, by event type.",
+	}, []string{"event_type"})
+
+	// KafkaPublishDuration tracks publish latency to Kafka, by topic.
+	KafkaPublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "events_kafka_publish_duration_seconds",
+		Help: "Latency of publishing a message to Kafka, by topic.",
+	}, []string{"topic"})
+
+	// KafkaPublishErrorsTotal counts Kafka publish failures, by topic.
+	KafkaPublishErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_kafka_publish_errors_total",
+		Help: "Kafka publish failures, by topic.",
+	}, []string{"topic"})
+
+	// ConsumerLag tracks the gap between a partition's high water mark and
+	// the last offset this service committed.
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "events_consumer_lag",
+		Help: "High water mark minus last committed offset, by topic and partition.",
+	}, []string{"topic", "partition"})
+
+	// MessagesProcessedTotal counts messages successfully processed, by
+	// topic.
+	MessagesProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_messages_processed_total",
+		Help: "Messages successfully processed, by topic.",
+	}, []string{"topic"})
+
+	// DLQPushesTotal counts messages republished to a dead-letter topic, by
+	// original topic.
+	DLQPushesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_dlq_pushes_total",
+		Help: "Messages republished to a dead-letter topic, by original topic.",
+	}, []string{"topic"})
+
+	// JSONDecodeFailuresTotal counts JSON decode failures, by stage
+	// (http_request or cloudevent).
+	JSONDecodeFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_json_decode_failures_total",
+		Help: "JSON decode failures, by stage.",
+	}, []string{"stage"})
+)